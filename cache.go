@@ -1,7 +1,10 @@
 package sample1
 
 import (
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,75 +15,285 @@ type price struct {
 	cachedAt time.Time
 }
 
-// priceError is an abstraction for the price and error
-type priceError struct {
-	price price
-	err   error
-}
-
 // PriceService is a service that we can use to get prices for the items
 // Calls to this service are expensive (they take time)
 type PriceService interface {
 	GetPriceFor(itemCode string) (float64, error)
 }
 
+// call represents a single, possibly still in-flight, call to the actual
+// price service for one itemCode. Concurrent callers for the same itemCode
+// share the same call instead of each hitting the backend, and all of them
+// receive its result once it completes.
+type call struct {
+	wg    sync.WaitGroup
+	value float64
+	err   error
+}
+
 // TransparentCache is a cache that wraps the actual service
 // The cache will remember prices we ask for, so that we don't have to wait on every call
 // Cache should only return a price if it is not older than "maxAge", so that we don't get stale prices
 type TransparentCache struct {
 	actualPriceService PriceService
 	maxAge             time.Duration
-	prices             map[string]price
+	staleGrace         time.Duration
+	storage            Storage
+	breaker            *circuitBreaker
+	maxConcurrency     int
+	persistPath        string
+	persistInterval    time.Duration
+	observer           Observer
+	stats              cacheStats
+
+	mu    sync.Mutex
+	calls map[string]*call
+
+	lastKnownMu sync.RWMutex
+	lastKnown   map[string]price
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	closeWg   sync.WaitGroup
+}
+
+// Option configures a TransparentCache. Options are applied in order by
+// NewTransparentCache.
+type Option func(*TransparentCache)
+
+// WithStorage makes the cache keep its entries in storage instead of the
+// default in-process map, e.g. to share cached prices across processes
+// with NewRedisStorage or NewMemcacheStorage.
+func WithStorage(storage Storage) Option {
+	return func(c *TransparentCache) {
+		c.storage = storage
+	}
+}
+
+// WithStaleWhileRevalidate lets GetPriceFor serve an entry that is older
+// than maxAge but not older than maxAge+grace: it returns the stale value
+// immediately and kicks off an asynchronous refresh from actualPriceService
+// in the background, instead of blocking the caller on the backend.
+func WithStaleWhileRevalidate(grace time.Duration) Option {
+	return func(c *TransparentCache) {
+		c.staleGrace = grace
+	}
+}
+
+// WithCircuitBreaker wraps every call to actualPriceService in a circuit
+// breaker configured by cfg, so that a struggling backend is given room to
+// recover instead of being hammered by every cache miss.
+func WithCircuitBreaker(cfg CircuitBreakerConfig) Option {
+	return func(c *TransparentCache) {
+		c.breaker = newCircuitBreaker(cfg)
+	}
+}
+
+// WithMaxConcurrency caps how many goroutines GetPricesFor and GetPricesForAll
+// run at once, via a fixed-size worker pool, so that batches of thousands of
+// itemCodes don't spawn a goroutine each. n <= 0 means unbounded: one
+// goroutine per itemCode (the default).
+func WithMaxConcurrency(n int) Option {
+	return func(c *TransparentCache) {
+		c.maxConcurrency = n
+	}
 }
 
 // NewTransparentCache is the implementation for PriceService interface
 // It creates a new Transparent Cache based on the arguments
-func NewTransparentCache(actualPriceService PriceService, maxAge time.Duration) *TransparentCache {
-	return &TransparentCache{
+func NewTransparentCache(actualPriceService PriceService, maxAge time.Duration, opts ...Option) *TransparentCache {
+	c := &TransparentCache{
 		actualPriceService: actualPriceService,
 		maxAge:             maxAge,
-		prices:             map[string]price{},
+		storage:            newMemoryStorage(),
+		calls:              map[string]*call{},
+		lastKnown:          map[string]price{},
+		closeCh:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.persistPath != "" {
+		c.loadPersisted()
+		c.closeWg.Add(1)
+		go func() {
+			defer c.closeWg.Done()
+			c.runPersistence(c.persistInterval)
+		}()
 	}
+	if c.maxAge > 0 {
+		c.closeWg.Add(1)
+		go func() {
+			defer c.closeWg.Done()
+			c.runJanitor(c.maxAge)
+		}()
+	}
+	return c
+}
+
+// Close stops the background janitor and persistence goroutines started by
+// NewTransparentCache, and waits for them to finish (including, for persistence,
+// the final flush to persistPath) before returning. It is safe to call Close
+// more than once.
+func (c *TransparentCache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+	c.closeWg.Wait()
 }
 
-// GetPriceFor gets the price for the item, either from the cache or the actual service if it was not cached or too old
+// GetPriceFor gets the price for the item, either from the cache or the actual service if it was not cached or too old.
+// Concurrent calls for the same itemCode are deduplicated: only one of them reaches actualPriceService,
+// and the rest wait for and share its result. If the cached entry is stale but still within the
+// WithStaleWhileRevalidate grace period, the stale value is returned immediately while a refresh
+// happens in the background.
 func (c *TransparentCache) GetPriceFor(itemCode string) (float64, error) {
-	if price, ok := c.prices[itemCode]; ok && time.Since(price.cachedAt) < c.maxAge {
-		return price.value, nil
+	if p, ok := c.storage.Get(itemCode); ok {
+		age := time.Since(p.cachedAt)
+		if age < c.maxAge {
+			c.recordHit(itemCode)
+			return p.value, nil
+		}
+		if c.staleGrace > 0 && age < c.maxAge+c.staleGrace {
+			c.recordHit(itemCode)
+			c.refresh(itemCode)
+			return p.value, nil
+		}
 	}
-	value, err := c.actualPriceService.GetPriceFor(itemCode)
-	if err != nil {
-		return 0, fmt.Errorf("getting price from service : %v", err.Error())
+	c.recordMiss(itemCode)
+
+	inFlight := c.refresh(itemCode)
+	inFlight.wg.Wait()
+	return inFlight.value, inFlight.err
+}
+
+// refresh returns the in-flight call for itemCode, starting a new one that asks
+// actualPriceService for a fresh value if none is already running.
+func (c *TransparentCache) refresh(itemCode string) *call {
+	c.mu.Lock()
+	if inFlight, ok := c.calls[itemCode]; ok {
+		c.mu.Unlock()
+		return inFlight
 	}
-	c.prices[itemCode] = price{value: value, cachedAt: time.Now()}
-	return value, nil
+	inFlight := &call{}
+	inFlight.wg.Add(1)
+	c.calls[itemCode] = inFlight
+	c.mu.Unlock()
+
+	go func() {
+		fetch := c.actualPriceService.GetPriceFor
+		if c.breaker != nil {
+			fetch = func(itemCode string) (float64, error) {
+				return c.breaker.Execute(func() (float64, error) {
+					return c.actualPriceService.GetPriceFor(itemCode)
+				})
+			}
+		}
+
+		atomic.AddInt64(&c.stats.inFlight, 1)
+		start := time.Now()
+		value, err := fetch(itemCode)
+		atomic.AddInt64(&c.stats.inFlight, -1)
+		if !errors.Is(err, ErrCircuitOpen) {
+			// The breaker rejected the call before it ever reached actualPriceService:
+			// there's no real backend call, duration or outcome to report.
+			c.recordBackendCall(itemCode, time.Since(start), err)
+		}
+
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			if stale, ok := c.getLastKnown(itemCode); ok {
+				value, err = stale.value, ErrStaleCache
+			}
+		case err != nil:
+			err = fmt.Errorf("getting price from service : %v", err.Error())
+		default:
+			p := price{value: value, cachedAt: time.Now()}
+			c.storage.Set(itemCode, p)
+			if c.breaker != nil {
+				c.setLastKnown(itemCode, p)
+			}
+		}
+
+		inFlight.value, inFlight.err = value, err
+
+		c.mu.Lock()
+		delete(c.calls, itemCode)
+		c.mu.Unlock()
+		inFlight.wg.Done()
+	}()
+
+	return inFlight
 }
 
-// GetPricesFor gets the prices for several items at once, some might be found in the cache, others might not
-// If any of the operations returns an error, it should return an error as well
+// GetPricesFor gets the prices for several items at once, some might be found in the cache, others might not.
+// The result preserves the order of itemCodes, so results[i] always corresponds to itemCodes[i].
+// If any of the operations returns an error, it returns that error, but still reports the (index-aligned)
+// results of the lookups that did complete. ErrStaleCache is not treated as a failure here: it carries a
+// usable, if aging, value and short-circuiting on it would defeat the point of degrading gracefully.
 func (c *TransparentCache) GetPricesFor(itemCodes ...string) ([]float64, error) {
-	ch := make(chan priceError, len(itemCodes))
-	for _, itemCode := range itemCodes {
-		go publishPrice(c.GetPriceFor, itemCode, ch)
+	results, errs := c.getAll(itemCodes)
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrStaleCache) {
+			return results, err
+		}
 	}
-	return consumePrices(ch)
+	return results, nil
 }
 
-// publishPrice publish the retrieved price to the queue (channel)
-func publishPrice(GetPriceFor func(string) (float64, error), itemCode string, ch chan priceError) {
-	value, err := GetPriceFor(itemCode)
-	ch <- priceError{price: price{value: value}, err: err}
+// GetPricesForAll gets the prices for several items at once, like GetPricesFor, but never short-circuits:
+// it runs every lookup to completion and returns a per-item error slice alongside the results, so a single
+// failing item does not hide the successful prices of the others. Both slices are index-aligned with itemCodes.
+func (c *TransparentCache) GetPricesForAll(itemCodes ...string) ([]float64, []error) {
+	return c.getAll(itemCodes)
 }
 
-// consumePrices consumes the queued prices from the given channel and returns them into an float64 slice
-func consumePrices(ch chan priceError) ([]float64, error) {
-	var results []float64
-	for i := cap(ch); i > 0; i-- {
-		priceError := <-ch
-		if priceError.err != nil {
-			return results, priceError.err
+// getAll fetches every itemCode concurrently, honouring WithMaxConcurrency, and returns index-aligned
+// results and errors. With WithMaxConcurrency set, a fixed pool of that many workers drains the
+// itemCodes so that a batch of thousands never spawns more than that many goroutines at once;
+// without it, every itemCode gets its own goroutine.
+func (c *TransparentCache) getAll(itemCodes []string) ([]float64, []error) {
+	results := make([]float64, len(itemCodes))
+	errs := make([]error, len(itemCodes))
+
+	fetch := func(i int) {
+		results[i], errs[i] = c.GetPriceFor(itemCodes[i])
+	}
+
+	if c.maxConcurrency <= 0 {
+		var wg sync.WaitGroup
+		wg.Add(len(itemCodes))
+		for i := range itemCodes {
+			go func(i int) {
+				defer wg.Done()
+				fetch(i)
+			}(i)
 		}
-		results = append(results, priceError.price.value)
+		wg.Wait()
+		return results, errs
 	}
-	return results, nil
+
+	indices := make(chan int, len(itemCodes))
+	for i := range itemCodes {
+		indices <- i
+	}
+	close(indices)
+
+	workers := c.maxConcurrency
+	if workers > len(itemCodes) {
+		workers = len(itemCodes)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				fetch(i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results, errs
 }
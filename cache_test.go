@@ -0,0 +1,211 @@
+package sample1
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingPriceService counts how many times GetPriceFor actually reaches the
+// backend, and optionally fails itemCodes listed in failFor.
+type countingPriceService struct {
+	calls   int64
+	failFor map[string]bool
+}
+
+func (s *countingPriceService) GetPriceFor(itemCode string) (float64, error) {
+	atomic.AddInt64(&s.calls, 1)
+	if s.failFor[itemCode] {
+		return 0, errors.New("backend unavailable")
+	}
+	return 1.23, nil
+}
+
+// TestGetPriceForDedupesConcurrentMisses asserts that concurrent callers
+// racing on the same cache miss share a single in-flight call to the backend,
+// per the singleflight contract described on refresh. Run with -race.
+func TestGetPriceForDedupesConcurrentMisses(t *testing.T) {
+	backend := &countingPriceService{}
+	c := NewTransparentCache(backend, time.Minute)
+	defer c.Close()
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetPriceFor("ITEM1"); err != nil {
+				t.Errorf("GetPriceFor returned an error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&backend.calls); got != 1 {
+		t.Fatalf("expected exactly 1 backend call for %d concurrent misses, got %d", n, got)
+	}
+}
+
+// TestGetPricesForAllIndexAligned checks that GetPricesForAll keeps its
+// results and errors index-aligned with the requested itemCodes even when
+// some of them fail.
+func TestGetPricesForAllIndexAligned(t *testing.T) {
+	backend := &countingPriceService{failFor: map[string]bool{"BAD1": true, "BAD2": true}}
+	c := NewTransparentCache(backend, time.Minute)
+	defer c.Close()
+
+	itemCodes := []string{"GOOD1", "BAD1", "GOOD2", "BAD2"}
+	results, errs := c.GetPricesForAll(itemCodes...)
+
+	if len(results) != len(itemCodes) || len(errs) != len(itemCodes) {
+		t.Fatalf("expected index-aligned slices of length %d, got %d results and %d errors", len(itemCodes), len(results), len(errs))
+	}
+	for i, itemCode := range itemCodes {
+		wantErr := backend.failFor[itemCode]
+		if gotErr := errs[i] != nil; gotErr != wantErr {
+			t.Errorf("itemCode %s (index %d): expected error=%v, got err=%v", itemCode, i, wantErr, errs[i])
+		}
+		if !wantErr && results[i] != 1.23 {
+			t.Errorf("itemCode %s (index %d): expected price 1.23, got %v", itemCode, i, results[i])
+		}
+	}
+}
+
+// TestGetPricesForShortCircuitsOnFirstError checks that GetPricesFor, unlike
+// GetPricesForAll, stops at the first error it sees but still reports the
+// (index-aligned) results gathered so far.
+func TestGetPricesForShortCircuitsOnFirstError(t *testing.T) {
+	backend := &countingPriceService{failFor: map[string]bool{"BAD1": true}}
+	c := NewTransparentCache(backend, time.Minute)
+	defer c.Close()
+
+	results, err := c.GetPricesFor("GOOD1", "BAD1")
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 index-aligned results, got %d", len(results))
+	}
+	if results[0] != 1.23 {
+		t.Errorf("expected GOOD1's result to be reported despite BAD1's error, got %v", results[0])
+	}
+}
+
+// TestGetPricesForDoesNotShortCircuitOnStaleCache checks that a degraded,
+// stale-cache result (served while the circuit breaker is open) does not
+// make GetPricesFor short-circuit the way a hard failure would.
+func TestGetPricesForDoesNotShortCircuitOnStaleCache(t *testing.T) {
+	backend := &countingPriceService{}
+	c := NewTransparentCache(backend, time.Millisecond, WithCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                time.Second,
+		SleepWindow:            time.Hour,
+		ErrorPercentThreshold:  1,
+		RequestVolumeThreshold: 1,
+	}))
+	defer c.Close()
+
+	if _, err := c.GetPriceFor("ITEM1"); err != nil {
+		t.Fatalf("warming the cache failed: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	backend.failFor = map[string]bool{"ITEM1": true}
+	if _, err := c.GetPriceFor("ITEM1"); err == nil {
+		t.Fatal("expected the warm-up refresh to fail and trip the breaker")
+	}
+
+	results, err := c.GetPricesFor("ITEM1")
+	if err != nil {
+		t.Fatalf("expected GetPricesFor to serve the stale value instead of short-circuiting, got: %v", err)
+	}
+	if results[0] != 1.23 {
+		t.Fatalf("expected the stale price to be reported, got %v", results[0])
+	}
+}
+
+// TestWithMaxConcurrencyCapsGoroutines checks that a batch far larger than
+// WithMaxConcurrency never has more than that many backend calls in flight
+// at once, i.e. that getAll uses a bounded worker pool rather than one
+// goroutine per itemCode gated by a semaphore.
+func TestWithMaxConcurrencyCapsGoroutines(t *testing.T) {
+	const maxConcurrency = 5
+	var inFlight, maxSeen int64
+	backend := blockingPriceService(func() {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt64(&maxSeen)
+			if n <= seen || atomic.CompareAndSwapInt64(&maxSeen, seen, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	})
+
+	c := NewTransparentCache(backend, time.Minute, WithMaxConcurrency(maxConcurrency))
+	defer c.Close()
+
+	itemCodes := make([]string, 200)
+	for i := range itemCodes {
+		itemCodes[i] = fmt.Sprintf("ITEM%d", i)
+	}
+	if _, errs := c.GetPricesForAll(itemCodes...); len(errs) != len(itemCodes) {
+		t.Fatalf("expected %d index-aligned errors, got %d", len(itemCodes), len(errs))
+	}
+
+	if got := atomic.LoadInt64(&maxSeen); got > maxConcurrency {
+		t.Fatalf("expected at most %d concurrent backend calls, saw %d", maxConcurrency, got)
+	}
+}
+
+// rejectingObserver counts how many times OnBackendCall fires, to check that
+// a circuit-breaker rejection (no real call to actualPriceService) doesn't
+// count as one.
+type rejectingObserver struct {
+	backendCalls int64
+}
+
+func (o *rejectingObserver) OnHit(key string)  {}
+func (o *rejectingObserver) OnMiss(key string) {}
+func (o *rejectingObserver) OnBackendCall(key string, dur time.Duration, err error) {
+	atomic.AddInt64(&o.backendCalls, 1)
+}
+func (o *rejectingObserver) OnEviction(key string) {}
+
+// TestRefreshDoesNotRecordBackendCallOnCircuitOpen checks that a call
+// rejected by the circuit breaker before it ever reaches actualPriceService
+// is not reported as a backend call: it would otherwise inflate Stats'
+// backendCalls/AverageBackendLatency and the OnBackendCall observer hook.
+func TestRefreshDoesNotRecordBackendCallOnCircuitOpen(t *testing.T) {
+	backend := &countingPriceService{failFor: map[string]bool{"ITEM1": true}}
+	obs := &rejectingObserver{}
+	c := NewTransparentCache(backend, time.Minute, WithMetricsObserver(obs), WithCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                time.Second,
+		SleepWindow:            time.Hour,
+		ErrorPercentThreshold:  1,
+		RequestVolumeThreshold: 1,
+	}))
+	defer c.Close()
+
+	if _, err := c.GetPriceFor("ITEM1"); err == nil {
+		t.Fatal("expected the first call to fail and trip the breaker")
+	}
+	if _, err := c.GetPriceFor("ITEM1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to reject the second call, got %v", err)
+	}
+
+	if got := atomic.LoadInt64(&obs.backendCalls); got != 1 {
+		t.Fatalf("expected exactly 1 recorded backend call (the breaker rejection should not count), got %d", got)
+	}
+}
+
+type blockingPriceService func()
+
+func (f blockingPriceService) GetPriceFor(itemCode string) (float64, error) {
+	f()
+	return 1.0, nil
+}
@@ -0,0 +1,199 @@
+package sample1
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the circuit breaker while it is open (or
+// half-open and already probing) and no cached value is available to fall
+// back on.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrStaleCache is returned alongside a cached value when the circuit
+// breaker is open: the value is still usable, but it may be older than
+// maxAge because the backend could not be reached to refresh it.
+var ErrStaleCache = errors.New("returning stale cached price: circuit breaker is open")
+
+// CircuitBreakerConfig configures the circuit breaker installed with
+// WithCircuitBreaker.
+type CircuitBreakerConfig struct {
+	// Timeout is the maximum time a single call to actualPriceService is
+	// allowed to take before it counts as a failure. Timeout <= 0 means no
+	// timeout is enforced.
+	Timeout time.Duration
+	// MaxConcurrentRequests caps how many calls to actualPriceService may be
+	// in flight at once. Extra calls are rejected with ErrCircuitOpen.
+	MaxConcurrentRequests int
+	// SleepWindow is how long the breaker stays open before allowing a
+	// single half-open probe request through.
+	SleepWindow time.Duration
+	// ErrorPercentThreshold is the failure percentage (0-100), measured
+	// since the rolling window last reset, above which the breaker trips open.
+	// Defaults to defaultErrorPercentThreshold if <= 0.
+	ErrorPercentThreshold int
+	// RequestVolumeThreshold is the minimum number of requests that must
+	// land in the rolling window before ErrorPercentThreshold is even
+	// evaluated, so that a single failing request (or a handful of them)
+	// can't trip the breaker on its own. Defaults to
+	// defaultRequestVolumeThreshold if <= 0.
+	RequestVolumeThreshold int
+	// RollingWindow is how long requests are accumulated towards
+	// ErrorPercentThreshold before the rolling window resets, independent of
+	// how long the breaker stays open once tripped (SleepWindow). Defaults
+	// to defaultRollingWindow if <= 0.
+	RollingWindow time.Duration
+}
+
+// Defaults applied to CircuitBreakerConfig fields left at their zero value,
+// matching the values hystrix ships with.
+const (
+	defaultRequestVolumeThreshold = 20
+	defaultRollingWindow          = 10 * time.Second
+	defaultErrorPercentThreshold  = 50
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker implements the classic closed/open/half-open state machine
+// around calls to the wrapped PriceService.
+type circuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu         sync.Mutex
+	state      circuitState
+	openedAt   time.Time
+	windowFrom time.Time
+	requests   int
+	failures   int
+	inFlight   int
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig) *circuitBreaker {
+	if cfg.RequestVolumeThreshold <= 0 {
+		cfg.RequestVolumeThreshold = defaultRequestVolumeThreshold
+	}
+	if cfg.RollingWindow <= 0 {
+		cfg.RollingWindow = defaultRollingWindow
+	}
+	if cfg.ErrorPercentThreshold <= 0 {
+		cfg.ErrorPercentThreshold = defaultErrorPercentThreshold
+	}
+	return &circuitBreaker{windowFrom: time.Now(), cfg: cfg}
+}
+
+// Execute runs fn, rejecting it with ErrCircuitOpen if the breaker is open
+// (or half-open with a probe already in flight), and counting a timeout as a
+// failure. A Timeout <= 0 means no timeout: fn is awaited for as long as it takes.
+func (b *circuitBreaker) Execute(fn func() (float64, error)) (float64, error) {
+	if err := b.beforeCall(); err != nil {
+		return 0, err
+	}
+
+	type result struct {
+		value float64
+		err   error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		b.releaseInFlight()
+		resCh <- result{value, err}
+	}()
+
+	var timeoutCh <-chan time.Time
+	if b.cfg.Timeout > 0 {
+		timeoutCh = time.After(b.cfg.Timeout)
+	}
+
+	select {
+	case res := <-resCh:
+		b.recordResult(res.err == nil)
+		return res.value, res.err
+	case <-timeoutCh:
+		// fn is still running in the goroutine above; releaseInFlight only
+		// frees its slot once that call actually returns, so MaxConcurrentRequests
+		// keeps counting it until then.
+		b.recordResult(false)
+		return 0, fmt.Errorf("circuit breaker: call to backend timed out after %s", b.cfg.Timeout)
+	}
+}
+
+// beforeCall decides whether a call may proceed, and reserves an in-flight slot if so.
+func (b *circuitBreaker) beforeCall() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitOpen {
+		if time.Since(b.openedAt) < b.cfg.SleepWindow {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+	}
+
+	if b.state == circuitHalfOpen && b.inFlight > 0 {
+		return ErrCircuitOpen
+	}
+
+	if b.cfg.MaxConcurrentRequests > 0 && b.inFlight >= b.cfg.MaxConcurrentRequests {
+		return ErrCircuitOpen
+	}
+
+	b.inFlight++
+	return nil
+}
+
+// recordResult records the outcome of a call admitted by beforeCall, and drives the state machine.
+// It does not release the call's in-flight slot: see releaseInFlight.
+func (b *circuitBreaker) recordResult(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitHalfOpen {
+		if success {
+			b.state = circuitClosed
+			b.resetWindowLocked()
+		} else {
+			b.state = circuitOpen
+			b.openedAt = time.Now()
+		}
+		return
+	}
+
+	if time.Since(b.windowFrom) > b.cfg.RollingWindow {
+		b.resetWindowLocked()
+	}
+
+	b.requests++
+	if !success {
+		b.failures++
+	}
+	if b.requests >= b.cfg.RequestVolumeThreshold && b.failures*100 >= b.requests*b.cfg.ErrorPercentThreshold {
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// releaseInFlight frees the in-flight slot reserved by beforeCall. Unlike recordResult, which is
+// driven by Execute's own view of the call (including a timeout), this only runs once fn itself
+// has actually returned, so a timed-out call keeps counting against MaxConcurrentRequests for as
+// long as it is genuinely still running against the backend.
+func (b *circuitBreaker) releaseInFlight() {
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) resetWindowLocked() {
+	b.windowFrom = time.Now()
+	b.requests = 0
+	b.failures = 0
+}
@@ -0,0 +1,163 @@
+package sample1
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func ok() (float64, error)   { return 1.0, nil }
+func fail() (float64, error) { return 0, errors.New("boom") }
+
+// TestCircuitBreakerRequestVolumeThreshold checks that a single failure (or a
+// handful of them) below RequestVolumeThreshold cannot trip the breaker on
+// its own, even at a 100% failure rate.
+func TestCircuitBreakerRequestVolumeThreshold(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                time.Second,
+		SleepWindow:            time.Hour,
+		RollingWindow:          time.Hour,
+		ErrorPercentThreshold:  50,
+		RequestVolumeThreshold: 5,
+	})
+
+	for i := 0; i < 4; i++ {
+		if _, err := b.Execute(fail); err == nil {
+			t.Fatalf("call %d: expected the wrapped call's own error to propagate", i)
+		}
+	}
+
+	if _, err := b.Execute(ok); errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("breaker tripped before reaching RequestVolumeThreshold")
+	}
+}
+
+// TestCircuitBreakerDefaultsErrorPercentThreshold checks that leaving
+// ErrorPercentThreshold unset does not make every request count as exceeding
+// it: an all-success backend must stay closed past RequestVolumeThreshold.
+func TestCircuitBreakerDefaultsErrorPercentThreshold(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                time.Second,
+		SleepWindow:            time.Hour,
+		RollingWindow:          time.Hour,
+		RequestVolumeThreshold: 5,
+	})
+
+	for i := 0; i < 10; i++ {
+		if _, err := b.Execute(ok); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: breaker tripped on an all-success backend with ErrorPercentThreshold unset", i)
+		}
+	}
+}
+
+// TestCircuitBreakerTransitions drives the breaker through
+// closed -> open -> half-open -> closed.
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                time.Second,
+		SleepWindow:            20 * time.Millisecond,
+		RollingWindow:          time.Hour,
+		ErrorPercentThreshold:  50,
+		RequestVolumeThreshold: 1,
+	})
+
+	if _, err := b.Execute(fail); err == nil {
+		t.Fatal("expected the wrapped call's own error to propagate")
+	}
+	if b.state != circuitOpen {
+		t.Fatalf("expected circuitOpen after tripping, got %v", b.state)
+	}
+
+	if _, err := b.Execute(ok); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen while inside SleepWindow, got %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+
+	if _, err := b.Execute(ok); err != nil {
+		t.Fatalf("expected the half-open probe to succeed, got %v", err)
+	}
+	if b.state != circuitClosed {
+		t.Fatalf("expected circuitClosed after a successful probe, got %v", b.state)
+	}
+}
+
+// TestCircuitBreakerHalfOpenProbeFailureReopens checks that a failing
+// half-open probe reopens the breaker instead of closing it.
+func TestCircuitBreakerHalfOpenProbeFailureReopens(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                time.Second,
+		SleepWindow:            10 * time.Millisecond,
+		RollingWindow:          time.Hour,
+		ErrorPercentThreshold:  50,
+		RequestVolumeThreshold: 1,
+	})
+
+	if _, err := b.Execute(fail); err == nil {
+		t.Fatal("expected the wrapped call's own error to propagate")
+	}
+	time.Sleep(15 * time.Millisecond)
+
+	if _, err := b.Execute(fail); err == nil {
+		t.Fatal("expected the failing half-open probe's own error to propagate")
+	}
+	if b.state != circuitOpen {
+		t.Fatalf("expected circuitOpen again after a failed probe, got %v", b.state)
+	}
+}
+
+// TestCircuitBreakerZeroTimeoutMeansNoTimeout checks that a zero Timeout does
+// not make every call time out immediately; a healthy, slightly slow backend
+// must be allowed to finish rather than being force-failed on arrival.
+func TestCircuitBreakerZeroTimeoutMeansNoTimeout(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		SleepWindow:            time.Hour,
+		RollingWindow:          time.Hour,
+		ErrorPercentThreshold:  50,
+		RequestVolumeThreshold: 1,
+	})
+
+	slow := func() (float64, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 1.0, nil
+	}
+	if _, err := b.Execute(slow); err != nil {
+		t.Fatalf("expected a zero Timeout to wait for the call, got %v", err)
+	}
+	if b.state != circuitClosed {
+		t.Fatalf("expected circuitClosed after a successful call, got %v", b.state)
+	}
+}
+
+// TestCircuitBreakerTimeoutKeepsInFlightUntilCallReturns checks that a timed
+// out call still counts against MaxConcurrentRequests until the backend call
+// it abandoned actually returns, instead of freeing its slot the moment
+// Execute gives up on waiting for it.
+func TestCircuitBreakerTimeoutKeepsInFlightUntilCallReturns(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		Timeout:                10 * time.Millisecond,
+		MaxConcurrentRequests:  1,
+		SleepWindow:            time.Hour,
+		RollingWindow:          time.Hour,
+		ErrorPercentThreshold:  100,
+		RequestVolumeThreshold: 1000, // high enough that one timeout doesn't also trip the breaker open
+	})
+
+	released := make(chan struct{})
+	go func() {
+		b.Execute(func() (float64, error) {
+			<-released
+			return 1.0, nil
+		})
+	}()
+	time.Sleep(30 * time.Millisecond) // let the call time out from Execute's point of view
+
+	if _, err := b.Execute(ok); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the still-running timed-out call to keep occupying the only MaxConcurrentRequests slot, got %v", err)
+	}
+	if b.state != circuitClosed {
+		t.Fatalf("expected the breaker to still be closed (only MaxConcurrentRequests should reject), got %v", b.state)
+	}
+
+	close(released)
+}
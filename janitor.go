@@ -0,0 +1,55 @@
+package sample1
+
+import "time"
+
+// runJanitor periodically evicts entries that have fallen out of the
+// stale-while-revalidate grace period (or out of maxAge, if no grace period
+// is configured), until Close is called. It is started by NewTransparentCache,
+// which only does so for a positive interval: NewTicker panics otherwise, and
+// a maxAge <= 0 never lets GetPriceFor treat an entry as fresh anyway.
+func (c *TransparentCache) runJanitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.evictExpired()
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+// evictExpired removes every entry older than maxAge (plus staleGrace, if set)
+// from storage. This only affects what GetPriceFor and the stale-while-revalidate
+// path see; it does not touch the separate lastKnown value a circuit breaker falls
+// back to, so an outage longer than one janitor tick still degrades gracefully.
+func (c *TransparentCache) evictExpired() {
+	cutoff := c.maxAge + c.staleGrace
+	for _, key := range c.storage.Keys() {
+		if p, ok := c.storage.Get(key); ok && time.Since(p.cachedAt) > cutoff {
+			c.storage.Delete(key)
+			c.recordEviction(key)
+		}
+	}
+}
+
+// setLastKnown records value as the last successfully fetched price for itemCode,
+// for the circuit breaker to fall back to while it is open. Unlike storage, this
+// is never evicted by maxAge or the janitor: it only ever changes when a fresh
+// value is fetched. Callers only invoke this when a circuit breaker is configured,
+// so a cache with no WithCircuitBreaker option never pays for this second map.
+func (c *TransparentCache) setLastKnown(itemCode string, value price) {
+	c.lastKnownMu.Lock()
+	defer c.lastKnownMu.Unlock()
+	c.lastKnown[itemCode] = value
+}
+
+// getLastKnown returns the last successfully fetched price for itemCode, if any,
+// regardless of how long ago it was fetched.
+func (c *TransparentCache) getLastKnown(itemCode string) (price, bool) {
+	c.lastKnownMu.RLock()
+	defer c.lastKnownMu.RUnlock()
+	p, ok := c.lastKnown[itemCode]
+	return p, ok
+}
@@ -0,0 +1,47 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+type constPriceService struct{}
+
+func (constPriceService) GetPriceFor(itemCode string) (float64, error) {
+	return 1.0, nil
+}
+
+// TestNewTransparentCacheNonPositiveMaxAgeDoesNotPanic guards against a
+// regression where a non-positive maxAge (a valid, if degenerate, "never
+// cache" configuration) made runJanitor call time.NewTicker with a
+// non-positive interval, which panics.
+func TestNewTransparentCacheNonPositiveMaxAgeDoesNotPanic(t *testing.T) {
+	for _, maxAge := range []time.Duration{0, -time.Second} {
+		c := NewTransparentCache(constPriceService{}, maxAge)
+		defer c.Close()
+
+		if _, err := c.GetPriceFor("ITEM1"); err != nil {
+			t.Fatalf("GetPriceFor returned an error: %v", err)
+		}
+	}
+}
+
+// TestEvictExpiredDoesNotTouchLastKnown checks that evictExpired, which
+// removes stale entries from storage, leaves the circuit breaker's
+// separately retained lastKnown value alone: an outage that outlasts a
+// janitor tick must not lose the fallback value it needs.
+func TestEvictExpiredDoesNotTouchLastKnown(t *testing.T) {
+	c := NewTransparentCache(constPriceService{}, time.Minute)
+	defer c.Close()
+
+	c.setLastKnown("ITEM1", price{value: 1.23, cachedAt: time.Now().Add(-time.Hour)})
+	c.evictExpired()
+
+	p, ok := c.getLastKnown("ITEM1")
+	if !ok {
+		t.Fatal("expected lastKnown to still hold ITEM1 after evictExpired")
+	}
+	if p.value != 1.23 {
+		t.Fatalf("expected the retained value to be unchanged, got %v", p.value)
+	}
+}
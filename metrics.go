@@ -0,0 +1,95 @@
+package sample1
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Observer receives cache lifecycle events as they happen, for operators who
+// want to feed them into their own metrics pipeline (see the prometheus
+// subpackage for a ready-made adapter) instead of, or in addition to, polling Stats().
+type Observer interface {
+	// OnHit is called when GetPriceFor is served from the cache, fresh or stale.
+	OnHit(key string)
+	// OnMiss is called when GetPriceFor has to ask actualPriceService for key.
+	OnMiss(key string)
+	// OnBackendCall is called after every call to actualPriceService, successful or not.
+	OnBackendCall(key string, dur time.Duration, err error)
+	// OnEviction is called when the background janitor removes an expired entry.
+	OnEviction(key string)
+}
+
+// WithMetricsObserver registers obs to be notified of cache hits, misses,
+// backend calls and evictions as they happen.
+func WithMetricsObserver(obs Observer) Option {
+	return func(c *TransparentCache) {
+		c.observer = obs
+	}
+}
+
+// Stats is a point-in-time snapshot of a TransparentCache's activity, as
+// returned by Stats().
+type Stats struct {
+	Hits                  uint64
+	Misses                uint64
+	Evictions             uint64
+	InFlightBackendCalls  int64
+	AverageBackendLatency time.Duration
+}
+
+// cacheStats holds the atomic counters backing Stats(). All fields are
+// accessed exclusively through sync/atomic.
+type cacheStats struct {
+	hits            uint64
+	misses          uint64
+	evictions       uint64
+	inFlight        int64
+	backendCalls    uint64
+	backendDuration int64 // cumulative nanoseconds
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counts, how many
+// backend calls are currently in flight, and their average latency.
+func (c *TransparentCache) Stats() Stats {
+	calls := atomic.LoadUint64(&c.stats.backendCalls)
+	var avg time.Duration
+	if calls > 0 {
+		avg = time.Duration(atomic.LoadInt64(&c.stats.backendDuration) / int64(calls))
+	}
+	return Stats{
+		Hits:                  atomic.LoadUint64(&c.stats.hits),
+		Misses:                atomic.LoadUint64(&c.stats.misses),
+		Evictions:             atomic.LoadUint64(&c.stats.evictions),
+		InFlightBackendCalls:  atomic.LoadInt64(&c.stats.inFlight),
+		AverageBackendLatency: avg,
+	}
+}
+
+func (c *TransparentCache) recordHit(key string) {
+	atomic.AddUint64(&c.stats.hits, 1)
+	if c.observer != nil {
+		c.observer.OnHit(key)
+	}
+}
+
+func (c *TransparentCache) recordMiss(key string) {
+	atomic.AddUint64(&c.stats.misses, 1)
+	if c.observer != nil {
+		c.observer.OnMiss(key)
+	}
+}
+
+func (c *TransparentCache) recordBackendCall(key string, dur time.Duration, err error) {
+	atomic.AddUint64(&c.stats.backendCalls, 1)
+	atomic.AddInt64(&c.stats.backendDuration, int64(dur))
+	if c.observer != nil {
+		c.observer.OnBackendCall(key, dur, err)
+	}
+}
+
+func (c *TransparentCache) recordEviction(key string) {
+	atomic.AddUint64(&c.stats.evictions, 1)
+	if c.observer != nil {
+		c.observer.OnEviction(key)
+	}
+}
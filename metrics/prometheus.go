@@ -0,0 +1,68 @@
+// Package metrics ships ready-made Observer adapters for TransparentCache.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cache "github.com/morarick/transparent-cache"
+)
+
+// PrometheusObserver implements cache.Observer, exposing cache hits, misses,
+// evictions and backend call latency as Prometheus metrics. Metrics are
+// aggregated across item codes rather than labelled by key: a catalogue can
+// have an unbounded number of item codes, and a label with unbounded
+// cardinality is exactly what a Prometheus scrape target cannot afford.
+type PrometheusObserver struct {
+	hits            prometheus.Counter
+	misses          prometheus.Counter
+	evictions       prometheus.Counter
+	backendCalls    *prometheus.CounterVec
+	backendDuration prometheus.Histogram
+}
+
+// NewPrometheusObserver creates a PrometheusObserver and registers its
+// metrics with reg.
+func NewPrometheusObserver(reg prometheus.Registerer) *PrometheusObserver {
+	o := &PrometheusObserver{
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "transparent_cache_hits_total",
+			Help: "Number of cache hits.",
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "transparent_cache_misses_total",
+			Help: "Number of cache misses.",
+		}),
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "transparent_cache_evictions_total",
+			Help: "Number of entries evicted by the background janitor.",
+		}),
+		backendCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "transparent_cache_backend_calls_total",
+			Help: "Number of calls made to the wrapped PriceService, by outcome.",
+		}, []string{"outcome"}),
+		backendDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "transparent_cache_backend_call_duration_seconds",
+			Help: "Latency of calls made to the wrapped PriceService.",
+		}),
+	}
+	reg.MustRegister(o.hits, o.misses, o.evictions, o.backendCalls, o.backendDuration)
+	return o
+}
+
+func (o *PrometheusObserver) OnHit(key string)  { o.hits.Inc() }
+func (o *PrometheusObserver) OnMiss(key string) { o.misses.Inc() }
+
+func (o *PrometheusObserver) OnBackendCall(key string, dur time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	o.backendCalls.WithLabelValues(outcome).Inc()
+	o.backendDuration.Observe(dur.Seconds())
+}
+
+func (o *PrometheusObserver) OnEviction(key string) { o.evictions.Inc() }
+
+var _ cache.Observer = (*PrometheusObserver)(nil)
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cache "github.com/morarick/transparent-cache"
+)
+
+type constPriceService struct{}
+
+func (constPriceService) GetPriceFor(itemCode string) (float64, error) {
+	return 1.0, nil
+}
+
+// TestPrometheusObserverWiresIntoCache checks that PrometheusObserver can be
+// registered and plugged into a TransparentCache as a cache.Observer, i.e.
+// that this subpackage actually builds against its cache and
+// client_golang dependencies rather than just compiling in isolation.
+func TestPrometheusObserverWiresIntoCache(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	obs := NewPrometheusObserver(reg)
+
+	c := cache.NewTransparentCache(constPriceService{}, time.Minute, cache.WithMetricsObserver(obs))
+	defer c.Close()
+
+	if _, err := c.GetPriceFor("ITEM1"); err != nil {
+		t.Fatalf("GetPriceFor returned an error: %v", err)
+	}
+
+	metricFamilies, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather returned an error: %v", err)
+	}
+	if len(metricFamilies) == 0 {
+		t.Fatal("expected the cache miss to have recorded at least one metric")
+	}
+}
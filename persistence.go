@@ -0,0 +1,90 @@
+package sample1
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// persistedEntry is the on-disk representation of a single cached price,
+// written and read by WithPersistence.
+type persistedEntry struct {
+	Key      string    `json:"key"`
+	Value    float64   `json:"value"`
+	CachedAt time.Time `json:"cached_at"`
+}
+
+// defaultPersistInterval is used by WithPersistence in place of a
+// non-positive flushInterval, which would otherwise make runPersistence
+// hand a non-positive interval to time.NewTicker and panic.
+const defaultPersistInterval = time.Minute
+
+// WithPersistence periodically serializes the cache's entries to path as JSON,
+// every flushInterval, and reloads them when NewTransparentCache starts, so that
+// warm cache state survives process restarts. Entries already older than maxAge
+// at load time are discarded. A non-positive flushInterval is replaced with
+// defaultPersistInterval.
+func WithPersistence(path string, flushInterval time.Duration) Option {
+	if flushInterval <= 0 {
+		flushInterval = defaultPersistInterval
+	}
+	return func(c *TransparentCache) {
+		c.persistPath = path
+		c.persistInterval = flushInterval
+	}
+}
+
+// loadPersisted reloads previously persisted entries from c.persistPath into c.storage.
+// It is a no-op if the file does not exist or cannot be parsed. An entry already past
+// maxAge+staleGrace is discarded, matching evictExpired's cutoff: anything still within
+// the grace window is kept, since GetPriceFor would have served it stale anyway.
+func (c *TransparentCache) loadPersisted() {
+	data, err := os.ReadFile(c.persistPath)
+	if err != nil {
+		return
+	}
+	var entries []persistedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	cutoff := c.maxAge + c.staleGrace
+	for _, e := range entries {
+		if time.Since(e.CachedAt) >= cutoff {
+			continue
+		}
+		c.storage.Set(e.Key, price{value: e.Value, cachedAt: e.CachedAt})
+	}
+}
+
+// persist writes every entry currently in c.storage to c.persistPath.
+func (c *TransparentCache) persist() {
+	keys := c.storage.Keys()
+	entries := make([]persistedEntry, 0, len(keys))
+	for _, key := range keys {
+		if p, ok := c.storage.Get(key); ok {
+			entries = append(entries, persistedEntry{Key: key, Value: p.value, CachedAt: p.cachedAt})
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(c.persistPath, data, 0o644)
+}
+
+// runPersistence flushes the cache to c.persistPath every interval until Close
+// is called, flushing one last time before it returns.
+func (c *TransparentCache) runPersistence(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.persist()
+		case <-c.closeCh:
+			c.persist()
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package sample1
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWithPersistenceNonPositiveFlushIntervalDoesNotPanic guards against a
+// regression where a non-positive flushInterval made runPersistence call
+// time.NewTicker with a non-positive interval, which panics.
+func TestWithPersistenceNonPositiveFlushIntervalDoesNotPanic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	for _, interval := range []time.Duration{0, -time.Second} {
+		c := NewTransparentCache(constPriceService{}, time.Minute, WithPersistence(path, interval))
+		if _, err := c.GetPriceFor("ITEM1"); err != nil {
+			t.Fatalf("GetPriceFor returned an error: %v", err)
+		}
+		c.Close()
+	}
+}
+
+// TestWithPersistenceReloadsAcrossRestart populates a cache, closes it (which
+// flushes to disk), and checks that a fresh TransparentCache pointed at the
+// same path reloads the price without ever calling actualPriceService again.
+func TestWithPersistenceReloadsAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+
+	c1 := NewTransparentCache(constPriceService{}, time.Minute, WithPersistence(path, time.Hour))
+	if _, err := c1.GetPriceFor("ITEM1"); err != nil {
+		t.Fatalf("GetPriceFor returned an error: %v", err)
+	}
+	c1.Close()
+
+	c2 := NewTransparentCache(failingPriceService{}, time.Minute, WithPersistence(path, time.Hour))
+	defer c2.Close()
+
+	got, err := c2.GetPriceFor("ITEM1")
+	if err != nil {
+		t.Fatalf("GetPriceFor returned an error: %v", err)
+	}
+	if got != 1.0 {
+		t.Fatalf("GetPriceFor = %v, want the persisted value 1.0", got)
+	}
+}
+
+// TestLoadPersistedDiscardsEntriesPastMaxAgePlusStaleGrace checks that loadPersisted
+// keeps an entry that is stale but still within maxAge+staleGrace (GetPriceFor would
+// serve it), and discards one that is past that combined cutoff.
+func TestLoadPersistedDiscardsEntriesPastMaxAgePlusStaleGrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	entries := []persistedEntry{
+		{Key: "WITHIN_GRACE", Value: 1.0, CachedAt: time.Now().Add(-90 * time.Second)},
+		{Key: "PAST_GRACE", Value: 2.0, CachedAt: time.Now().Add(-3 * time.Minute)},
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("json.Marshal returned an error: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("os.WriteFile returned an error: %v", err)
+	}
+
+	c := NewTransparentCache(failingPriceService{}, time.Minute,
+		WithPersistence(path, time.Hour), WithStaleWhileRevalidate(time.Minute))
+	defer c.Close()
+
+	if _, ok := c.storage.Get("WITHIN_GRACE"); !ok {
+		t.Fatalf("loadPersisted discarded an entry still within maxAge+staleGrace")
+	}
+	if _, ok := c.storage.Get("PAST_GRACE"); ok {
+		t.Fatalf("loadPersisted kept an entry past maxAge+staleGrace")
+	}
+}
+
+type failingPriceService struct{}
+
+func (failingPriceService) GetPriceFor(itemCode string) (float64, error) {
+	return 0, errors.New("backend should not have been called")
+}
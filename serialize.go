@@ -0,0 +1,32 @@
+package sample1
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+// encodedPrice mirrors price with exported fields, since gob cannot encode
+// the unexported fields of price directly.
+type encodedPrice struct {
+	Value    float64
+	CachedAt time.Time
+}
+
+// encodePrice serializes a price for storage in an out-of-process backend.
+func encodePrice(p price) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(encodedPrice{Value: p.value, CachedAt: p.cachedAt}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodePrice deserializes a price previously written by encodePrice.
+func decodePrice(data []byte) (price, error) {
+	var ep encodedPrice
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&ep); err != nil {
+		return price{}, err
+	}
+	return price{value: ep.Value, cachedAt: ep.CachedAt}, nil
+}
@@ -0,0 +1,26 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEncodeDecodePriceRoundTrip checks that decodePrice recovers exactly
+// what encodePrice wrote, as required for a price to survive a trip through
+// an out-of-process Storage backend.
+func TestEncodeDecodePriceRoundTrip(t *testing.T) {
+	want := price{value: 12.34, cachedAt: time.Now().Round(0)}
+
+	data, err := encodePrice(want)
+	if err != nil {
+		t.Fatalf("encodePrice returned an error: %v", err)
+	}
+
+	got, err := decodePrice(data)
+	if err != nil {
+		t.Fatalf("decodePrice returned an error: %v", err)
+	}
+	if !got.cachedAt.Equal(want.cachedAt) || got.value != want.value {
+		t.Fatalf("decodePrice(encodePrice(p)) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,15 @@
+package sample1
+
+// Storage abstracts where cached prices live, so that TransparentCache can
+// be backed by a plain in-process map or by a shared, out-of-process store
+// such as Redis or Memcache.
+type Storage interface {
+	// Get returns the stored price for key, and whether it was found.
+	Get(key string) (price, bool)
+	// Set stores value for key, replacing any previous entry.
+	Set(key string, value price)
+	// Delete removes key from the store, if present.
+	Delete(key string)
+	// Keys returns all keys currently held in the store.
+	Keys() []string
+}
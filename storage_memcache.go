@@ -0,0 +1,76 @@
+package sample1
+
+import (
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// memcacheStorage is a Storage backed by a shared Memcache instance.
+//
+// Memcache has no native key-listing command, so memcacheStorage keeps a
+// local set of the keys it has written to support Keys(); this set can
+// drift from reality if entries are evicted by Memcache itself under
+// memory pressure.
+type memcacheStorage struct {
+	client *memcache.Client
+	prefix string
+	expiry int32
+
+	mu   sync.RWMutex
+	keys map[string]struct{}
+}
+
+// NewMemcacheStorage creates a Storage backed by client. Keys are namespaced
+// with prefix to avoid clashing with unrelated data in the same Memcache
+// instance. expirySeconds is passed straight through to Memcache as the
+// entry's expiration.
+func NewMemcacheStorage(client *memcache.Client, prefix string, expirySeconds int32) Storage {
+	return &memcacheStorage{client: client, prefix: prefix, expiry: expirySeconds, keys: map[string]struct{}{}}
+}
+
+func (s *memcacheStorage) key(itemCode string) string {
+	return s.prefix + itemCode
+}
+
+func (s *memcacheStorage) Get(key string) (price, bool) {
+	item, err := s.client.Get(s.key(key))
+	if err != nil {
+		return price{}, false
+	}
+	p, err := decodePrice(item.Value)
+	if err != nil {
+		return price{}, false
+	}
+	return p, true
+}
+
+func (s *memcacheStorage) Set(key string, value price) {
+	data, err := encodePrice(value)
+	if err != nil {
+		return
+	}
+	if err := s.client.Set(&memcache.Item{Key: s.key(key), Value: data, Expiration: s.expiry}); err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.keys[key] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *memcacheStorage) Delete(key string) {
+	s.client.Delete(s.key(key))
+	s.mu.Lock()
+	delete(s.keys, key)
+	s.mu.Unlock()
+}
+
+func (s *memcacheStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.keys))
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,43 @@
+package sample1
+
+import "sync"
+
+// memoryStorage is the default Storage: a plain in-process map guarded by
+// its own RWMutex.
+type memoryStorage struct {
+	mu     sync.RWMutex
+	prices map[string]price
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{prices: map[string]price{}}
+}
+
+func (s *memoryStorage) Get(key string) (price, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.prices[key]
+	return p, ok
+}
+
+func (s *memoryStorage) Set(key string, value price) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[key] = value
+}
+
+func (s *memoryStorage) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.prices, key)
+}
+
+func (s *memoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.prices))
+	for key := range s.prices {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,61 @@
+package sample1
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisStorage is a Storage backed by a shared Redis instance, so that
+// cached prices can be reused across multiple processes.
+type redisStorage struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStorage creates a Storage backed by client. Keys are namespaced
+// with prefix to avoid clashing with unrelated data in the same Redis
+// instance, and entries are written with ttl as their Redis expiration so
+// that stale prices are reclaimed even if this process never evicts them.
+func NewRedisStorage(client *redis.Client, prefix string, ttl time.Duration) Storage {
+	return &redisStorage{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (s *redisStorage) key(itemCode string) string {
+	return s.prefix + itemCode
+}
+
+func (s *redisStorage) Get(key string) (price, bool) {
+	data, err := s.client.Get(context.Background(), s.key(key)).Bytes()
+	if err != nil {
+		return price{}, false
+	}
+	p, err := decodePrice(data)
+	if err != nil {
+		return price{}, false
+	}
+	return p, true
+}
+
+func (s *redisStorage) Set(key string, value price) {
+	data, err := encodePrice(value)
+	if err != nil {
+		return
+	}
+	s.client.Set(context.Background(), s.key(key), data, s.ttl)
+}
+
+func (s *redisStorage) Delete(key string) {
+	s.client.Del(context.Background(), s.key(key))
+}
+
+func (s *redisStorage) Keys() []string {
+	var keys []string
+	iter := s.client.Scan(context.Background(), 0, s.prefix+"*", 0).Iterator()
+	for iter.Next(context.Background()) {
+		keys = append(keys, iter.Val()[len(s.prefix):])
+	}
+	return keys
+}
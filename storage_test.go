@@ -0,0 +1,42 @@
+package sample1
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestMemoryStorageGetSetDeleteKeys exercises the Storage contract against
+// memoryStorage: a miss before Set, a hit with the same value after Set, no
+// trace of the key after Delete, and Keys reflecting what is currently held.
+func TestMemoryStorageGetSetDeleteKeys(t *testing.T) {
+	s := newMemoryStorage()
+
+	if _, ok := s.Get("ITEM1"); ok {
+		t.Fatalf("Get on empty storage found a value")
+	}
+
+	p1 := price{value: 1.23, cachedAt: time.Now()}
+	s.Set("ITEM1", p1)
+	if got, ok := s.Get("ITEM1"); !ok || got != p1 {
+		t.Fatalf("Get after Set = %v, %v, want %v, true", got, ok, p1)
+	}
+
+	p2 := price{value: 4.56, cachedAt: time.Now()}
+	s.Set("ITEM2", p2)
+
+	keys := s.Keys()
+	sort.Strings(keys)
+	if want := []string{"ITEM1", "ITEM2"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Keys() = %v, want %v", keys, want)
+	}
+
+	s.Delete("ITEM1")
+	if _, ok := s.Get("ITEM1"); ok {
+		t.Fatalf("Get found ITEM1 after Delete")
+	}
+	if keys := s.Keys(); !reflect.DeepEqual(keys, []string{"ITEM2"}) {
+		t.Fatalf("Keys() after Delete = %v, want [ITEM2]", keys)
+	}
+}